@@ -0,0 +1,203 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretKeyRef references a single key within a Secret.
+type SecretKeyRef struct {
+	// Name is the name of the Secret.
+	Name string `json:"name"`
+
+	// Key is the key within the Secret whose value is mounted.
+	Key string `json:"key"`
+}
+
+// ContainerConfigFile describes a single file to be mounted into a container.
+type ContainerConfigFile struct {
+	// Path is the absolute path, inside the container, at which the file is mounted.
+	Path string `json:"path"`
+
+	// Value is the inline content of the file.
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// FromSecret sources the file content from a key in a Secret instead of an
+	// inline value. Exactly one of Value or FromSecret should be set.
+	// +optional
+	FromSecret *SecretKeyRef `json:"fromSecret,omitempty"`
+}
+
+// ContainerConfig associates a set of configuration files with one of the
+// containers in spec.podSpec.containers, identified by name.
+type ContainerConfig struct {
+	// Name is the name of the container, as given in spec.podSpec.containers[].name,
+	// that these files should be mounted into.
+	Name string `json:"name"`
+
+	// Config lists the files to project into the named container.
+	// +optional
+	Config []ContainerConfigFile `json:"config,omitempty"`
+}
+
+// ContainerizedSpec defines the desired state of Containerized
+type ContainerizedSpec struct {
+	// PodSpec is the pod specification for the workload.
+	PodSpec corev1.PodSpec `json:"podSpec,omitempty"`
+
+	// Replicas is the desired number of replicas for the workload.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Containers carries additional per-container configuration, such as
+	// files to mount, keyed by container name.
+	// +optional
+	Containers []ContainerConfig `json:"containers,omitempty"`
+
+	// Service configures how the workload is exposed via a Service. If
+	// omitted, every container port is exposed on a ClusterIP Service.
+	// +optional
+	Service *ServiceSpec `json:"service,omitempty"`
+}
+
+// ServiceExposeType selects the kind of Service used to expose a
+// Containerized workload.
+type ServiceExposeType string
+
+const (
+	// ServiceExposeTypeClusterIP exposes the workload on a cluster-internal IP. This is the default.
+	ServiceExposeTypeClusterIP ServiceExposeType = "ClusterIP"
+	// ServiceExposeTypeNodePort exposes the workload on each Node's IP at a static port.
+	ServiceExposeTypeNodePort ServiceExposeType = "NodePort"
+	// ServiceExposeTypeLoadBalancer exposes the workload externally using a cloud provider's load balancer.
+	ServiceExposeTypeLoadBalancer ServiceExposeType = "LoadBalancer"
+	// ServiceExposeTypeNone creates a headless Service (ClusterIP: None) with no virtual IP.
+	ServiceExposeTypeNone ServiceExposeType = "None"
+)
+
+// ServicePort exposes a single named container port through the Service. The
+// name must match a port name in one of spec.podSpec.containers[].ports.
+type ServicePort struct {
+	// Name is the container port name to expose.
+	Name string `json:"name"`
+
+	// Port is the port the Service listens on. Defaults to the container port.
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// TargetPort overrides the container port the Service forwards traffic
+	// to. Defaults to the container port.
+	// +optional
+	TargetPort int32 `json:"targetPort,omitempty"`
+
+	// NodePort is the node port to allocate when Type is NodePort.
+	// +optional
+	NodePort int32 `json:"nodePort,omitempty"`
+}
+
+// ServiceSpec configures whether and how a Containerized workload is exposed
+// via an owned Service.
+type ServiceSpec struct {
+	// Disabled opts the workload out of Service exposure entirely. No
+	// Service is created, and any Service previously owned by this workload
+	// is deleted.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Type selects how the workload is exposed. Defaults to ClusterIP.
+	// +optional
+	Type ServiceExposeType `json:"type,omitempty"`
+
+	// Ports lists the container ports to expose, and how. If empty, every
+	// container port is exposed under its own name with the same port number.
+	// +optional
+	Ports []ServicePort `json:"ports,omitempty"`
+
+	// LoadBalancerClass is forwarded to the Service's spec.loadBalancerClass.
+	// Only meaningful when Type is LoadBalancer.
+	// +optional
+	LoadBalancerClass *string `json:"loadBalancerClass,omitempty"`
+
+	// ExternalTrafficPolicy is forwarded to the Service's spec.externalTrafficPolicy.
+	// +optional
+	ExternalTrafficPolicy corev1.ServiceExternalTrafficPolicyType `json:"externalTrafficPolicy,omitempty"`
+
+	// SessionAffinity is forwarded to the Service's spec.sessionAffinity.
+	// +optional
+	SessionAffinity corev1.ServiceAffinity `json:"sessionAffinity,omitempty"`
+}
+
+// HealthStatus is a human-facing summary of the workload's aggregated health,
+// consumable by a parent HealthScope.
+type HealthStatus string
+
+const (
+	// HealthStatusHealthy means the Deployment has observed the latest
+	// generation, every replica is ready, and the Service has endpoints.
+	HealthStatusHealthy HealthStatus = "Healthy"
+	// HealthStatusUnhealthy means one or more of the above conditions is not met.
+	HealthStatusUnhealthy HealthStatus = "Unhealthy"
+	// HealthStatusUnknown means health has not been evaluated yet.
+	HealthStatusUnknown HealthStatus = "Unknown"
+)
+
+// ContainerizedStatus defines the observed state of Containerized
+type ContainerizedStatus struct {
+	runtimev1alpha1.ConditionedStatus `json:",inline"`
+
+	// Resources list the resources managed by this workload.
+	// +optional
+	Resources []runtimev1alpha1.TypedReference `json:"resources,omitempty"`
+
+	// HealthStatus aggregates the readiness of the owned Deployment and
+	// Service, for consumption by a parent HealthScope.
+	// +optional
+	HealthStatus HealthStatus `json:"healthStatus,omitempty"`
+
+	// HealthMessage is a human-readable explanation of HealthStatus.
+	// +optional
+	HealthMessage string `json:"healthMessage,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// Containerized is the Schema for the containerizeds API
+type Containerized struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ContainerizedSpec   `json:"spec,omitempty"`
+	Status ContainerizedStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ContainerizedList contains a list of Containerized
+type ContainerizedList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Containerized `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Containerized{}, &ContainerizedList{})
+}
@@ -0,0 +1,60 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerized
+
+import (
+	"fmt"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TestOwnedTypes_MetadataOnlyForDeploymentAndService verifies the wiring
+// SetupWithManager relies on to avoid caching full Deployment and Service
+// objects: only those two kinds are marked metadata-only, so the manager's
+// cache stores PartialObjectMetadata for every Deployment and Service in the
+// cluster instead of their full specs, while ConfigMap - whose full object
+// this controller's up-to-date check needs - keeps a full-object watch. A
+// live cache-size comparison would need a running API server, which this
+// repository's test suite does not stand up; asserting the metadataOnly
+// flag per owned kind is the unit-testable proxy for that behavior.
+func TestOwnedTypes_MetadataOnlyForDeploymentAndService(t *testing.T) {
+	want := map[string]bool{
+		fmt.Sprintf("%T", &appsv1.Deployment{}): true,
+		fmt.Sprintf("%T", &corev1.Service{}):    true,
+		fmt.Sprintf("%T", &corev1.ConfigMap{}):  false,
+	}
+
+	got := map[string]bool{}
+	for _, ot := range ownedTypes {
+		got[fmt.Sprintf("%T", ot.object)] = ot.metadataOnly
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %d owned types, got %d: %v", len(want), len(got), got)
+	}
+	for kind, metadataOnly := range want {
+		gotMetadataOnly, ok := got[kind]
+		if !ok {
+			t.Fatalf("expected %s to be an owned type, but it is not registered", kind)
+		}
+		if gotMetadataOnly != metadataOnly {
+			t.Errorf("expected %s metadataOnly=%v, got %v", kind, metadataOnly, gotMetadataOnly)
+		}
+	}
+}
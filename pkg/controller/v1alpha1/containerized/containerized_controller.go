@@ -20,6 +20,8 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 
 	cpv1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
@@ -33,17 +35,21 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/cloud-native-application/rudrx/api/v1alpha1"
+	"github.com/cloud-native-application/rudrx/utils/update"
 )
 
 // Reconcile error strings.
 const (
 	errRenderDeployment = "cannot render deployment"
 	errRenderService    = "cannot render service"
+	errRenderConfigMap  = "cannot render configmap"
 	errApplyDeployment  = "cannot apply the deployment"
 	errApplyService     = "cannot apply the service"
+	errApplyConfigMap   = "cannot apply the configmap"
 )
 
 var (
@@ -51,6 +57,8 @@ var (
 	deploymentAPIVersion = appsv1.SchemeGroupVersion.String()
 	serviceKind          = reflect.TypeOf(corev1.Service{}).Name()
 	serviceAPIVersion    = corev1.SchemeGroupVersion.String()
+	configMapKind        = reflect.TypeOf(corev1.ConfigMap{}).Name()
+	configMapAPIVersion  = corev1.SchemeGroupVersion.String()
 )
 
 const (
@@ -63,12 +71,19 @@ type ContainerizedReconciler struct {
 	log    logr.Logger
 	record event.Recorder
 	Scheme *runtime.Scheme
+	// apiReader reads directly from the API server, bypassing the cache.
+	// It is used for reads against kinds that are only watched as
+	// metadata (Deployment, Service) so that, e.g., health computation can
+	// still observe fields that the metadata-only cache does not carry.
+	apiReader client.Reader
 }
 
 // +kubebuilder:rbac:groups=standard.oam.dev,resources=containerizeds,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=standard.oam.dev,resources=containerizeds/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=,resources=endpoints,verbs=get;list;watch
 func (r *ContainerizedReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	_ = context.Background()
 	ctx := context.Background()
@@ -90,26 +105,45 @@ func (r *ContainerizedReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 		log.Error(err, "workload", "name", workload.Name)
 		eventObj = &workload
 	}
-	deploy, err := r.renderDeployment(ctx, &workload)
+	configMap, err := r.renderConfigMap(ctx, &workload)
+	if err != nil {
+		log.Error(err, "Failed to render a configmap")
+		r.record.Event(eventObj, event.Warning(errRenderConfigMap, err))
+		return util.ReconcileWaitResult,
+			util.PatchCondition(ctx, r, &workload, cpv1alpha1.ReconcileError(errors.Wrap(err, errRenderConfigMap)))
+	}
+
+	deploy, err := r.renderDeployment(ctx, &workload, configMap)
 	if err != nil {
 		log.Error(err, "Failed to render a deployment")
 		r.record.Event(eventObj, event.Warning(errRenderDeployment, err))
 		return util.ReconcileWaitResult,
 			util.PatchCondition(ctx, r, &workload, cpv1alpha1.ReconcileError(errors.Wrap(err, errRenderDeployment)))
 	}
-	// merge patch
-	applyOpts := []client.PatchOption{client.ForceOwnership, client.FieldOwner(workload.GetUID())}
-	if err := r.Patch(ctx, deploy, client.Merge, applyOpts...); err != nil {
+
+	if configMap != nil {
+		if err := update.ReconcileConfigMap(ctx, r.Client, configMap); err != nil {
+			log.Error(err, "Failed to apply a configmap")
+			r.record.Event(eventObj, event.Warning(errApplyConfigMap, err))
+			return util.ReconcileWaitResult,
+				util.PatchCondition(ctx, r, &workload, cpv1alpha1.ReconcileError(errors.Wrap(err, errApplyConfigMap)))
+		}
+		r.record.Event(eventObj, event.Normal("ConfigMap created",
+			fmt.Sprintf("Workload `%s` successfully applied a configmap `%s`",
+				workload.Name, configMap.Name)))
+	}
+
+	if err := update.ReconcileDeployment(ctx, r.apiReader, r.Client, deploy); err != nil {
 		log.Error(err, "Failed to apply to a deployment")
 		r.record.Event(eventObj, event.Warning(errApplyDeployment, err))
 		return util.ReconcileWaitResult,
 			util.PatchCondition(ctx, r, &workload, cpv1alpha1.ReconcileError(errors.Wrap(err, errApplyDeployment)))
 	}
 	r.record.Event(eventObj, event.Normal("Deployment created",
-		fmt.Sprintf("Workload `%s` successfully patched a deployment `%s`",
+		fmt.Sprintf("Workload `%s` successfully applied a deployment `%s`",
 			workload.Name, deploy.Name)))
 
-	// create a service for the workload
+	// create a service for the workload, unless the user opted out of exposure
 	service, err := r.renderService(ctx, &workload)
 	if err != nil {
 		log.Error(err, "Failed to render a service")
@@ -117,18 +151,26 @@ func (r *ContainerizedReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 		return util.ReconcileWaitResult,
 			util.PatchCondition(ctx, r, &workload, cpv1alpha1.ReconcileError(errors.Wrap(err, errRenderService)))
 	}
-	// merge apply the service
-	if err := r.Patch(ctx, service, client.Merge, applyOpts...); err != nil {
-		log.Error(err, "Failed to apply a service")
-		r.record.Event(eventObj, event.Warning(errApplyDeployment, err))
-		return util.ReconcileWaitResult,
-			util.PatchCondition(ctx, r, &workload, cpv1alpha1.ReconcileError(errors.Wrap(err, errApplyService)))
+	if service == nil {
+		if err := r.deleteOwnedService(ctx, &workload); err != nil {
+			log.Error(err, "Failed to delete the service")
+			r.record.Event(eventObj, event.Warning(errApplyService, err))
+			return util.ReconcileWaitResult,
+				util.PatchCondition(ctx, r, &workload, cpv1alpha1.ReconcileError(errors.Wrap(err, errApplyService)))
+		}
+	} else {
+		if err := update.ReconcileService(ctx, r.apiReader, r.Client, service); err != nil {
+			log.Error(err, "Failed to apply a service")
+			r.record.Event(eventObj, event.Warning(errApplyService, err))
+			return util.ReconcileWaitResult,
+				util.PatchCondition(ctx, r, &workload, cpv1alpha1.ReconcileError(errors.Wrap(err, errApplyService)))
+		}
+		r.record.Event(eventObj, event.Normal("Service created",
+			fmt.Sprintf("Workload `%s` successfully applied a service `%s`",
+				workload.Name, service.Name)))
 	}
-	r.record.Event(eventObj, event.Normal("Service created",
-		fmt.Sprintf("Workload `%s` successfully server side patched a service `%s`",
-			workload.Name, service.Name)))
 
-	// record the new deployment, new service
+	// record the new deployment, and the service if exposure is enabled
 	workload.Status.Resources = []cpv1alpha1.TypedReference{
 		{
 			APIVersion: deploy.GetObjectKind().GroupVersionKind().GroupVersion().String(),
@@ -136,23 +178,111 @@ func (r *ContainerizedReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 			Name:       deploy.GetName(),
 			UID:        deploy.UID,
 		},
-		{
+	}
+	if service != nil {
+		workload.Status.Resources = append(workload.Status.Resources, cpv1alpha1.TypedReference{
 			APIVersion: service.GetObjectKind().GroupVersionKind().GroupVersion().String(),
 			Kind:       service.GetObjectKind().GroupVersionKind().Kind,
 			Name:       service.GetName(),
 			UID:        service.UID,
-		},
+		})
+	}
+	if configMap != nil {
+		workload.Status.Resources = append(workload.Status.Resources, cpv1alpha1.TypedReference{
+			APIVersion: configMap.GetObjectKind().GroupVersionKind().GroupVersion().String(),
+			Kind:       configMap.GetObjectKind().GroupVersionKind().Kind,
+			Name:       configMap.GetName(),
+			UID:        configMap.UID,
+		})
+	}
+
+	previousHealth := workload.Status.HealthStatus
+	health, healthMessage, err := r.computeHealth(ctx, &workload, service != nil)
+	if err != nil {
+		log.Error(err, "Failed to compute health status")
+		return util.ReconcileWaitResult, err
+	}
+	workload.Status.HealthStatus = health
+	workload.Status.HealthMessage = healthMessage
+	if health != previousHealth {
+		if health == v1alpha1.HealthStatusHealthy {
+			r.record.Event(eventObj, event.Normal("Workload is healthy", healthMessage))
+		} else {
+			r.record.Event(eventObj, event.Warning("Workload is unhealthy", errors.New(healthMessage)))
+		}
 	}
 
 	if err := r.Status().Update(ctx, &workload); err != nil {
 		return util.ReconcileWaitResult, err
 	}
+	if health != v1alpha1.HealthStatusHealthy {
+		return ctrl.Result{RequeueAfter: unhealthyRequeueAfter},
+			util.PatchCondition(ctx, r, &workload, cpv1alpha1.ReconcileSuccess())
+	}
 	return ctrl.Result{}, util.PatchCondition(ctx, r, &workload, cpv1alpha1.ReconcileSuccess())
 }
 
+// configMapNameForWorkload is the name of the ConfigMap holding the inline
+// configuration file values for a workload.
+func configMapNameForWorkload(workload *v1alpha1.Containerized) string {
+	return workload.GetName() + "-config"
+}
+
+// renderConfigMap synthesizes a ConfigMap holding the inline values of every
+// ContainerConfigFile across all containers. It returns nil if the workload
+// declares no inline configuration files.
+func (r *ContainerizedReconciler) renderConfigMap(ctx context.Context,
+	workload *v1alpha1.Containerized) (*corev1.ConfigMap, error) {
+	data := map[string]string{}
+	for _, cc := range workload.Spec.Containers {
+		for _, f := range cc.Config {
+			if f.FromSecret != nil {
+				continue
+			}
+			data[configMapKeyForFile(cc.Name, f.Path)] = f.Value
+		}
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       configMapKind,
+			APIVersion: configMapAPIVersion,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMapNameForWorkload(workload),
+			Namespace: workload.GetNamespace(),
+		},
+		Data: data,
+	}
+	util.PassLabelAndAnnotation(workload, cm)
+
+	if err := ctrl.SetControllerReference(workload, cm, r.Scheme); err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
+
+// configMapKeyForFile derives a stable, unique key for a file mounted into a
+// given container, scoped by the file's full path rather than just its base
+// name, so that two files in the same container with the same name in
+// different directories (e.g. /etc/foo/app.conf and /etc/bar/app.conf) don't
+// collide. The same key is also used as the file's subPath in
+// mountContainerConfigs, so it must be unique across every file projected
+// into a container, regardless of whether it is sourced from the workload's
+// ConfigMap or a referenced Secret. Every "/" in the path becomes "-", so
+// paths that differ only in "/" versus "-" (e.g. /etc/a-b and /etc/a/b)
+// map to the same key.
+func configMapKeyForFile(containerName, path string) string {
+	sanitized := strings.ReplaceAll(strings.TrimPrefix(path, "/"), "/", "-")
+	return containerName + "-" + sanitized
+}
+
 // create a corresponding deployment
 func (r *ContainerizedReconciler) renderDeployment(ctx context.Context,
-	workload *v1alpha1.Containerized) (*appsv1.Deployment, error) {
+	workload *v1alpha1.Containerized, configMap *corev1.ConfigMap) (*appsv1.Deployment, error) {
 	// generate the deployment
 	deploy := &appsv1.Deployment{
 		TypeMeta: metav1.TypeMeta{
@@ -180,6 +310,11 @@ func (r *ContainerizedReconciler) renderDeployment(ctx context.Context,
 			},
 		},
 	}
+
+	if err := mountContainerConfigs(&deploy.Spec.Template.Spec, workload.Spec.Containers, configMap); err != nil {
+		return nil, err
+	}
+
 	// pass through label and annotation from the workload to the deployment
 	util.PassLabelAndAnnotation(workload, deploy)
 	// pass through label and annotation from the workload to the pod template too
@@ -195,10 +330,121 @@ func (r *ContainerizedReconciler) renderDeployment(ctx context.Context,
 	return deploy, nil
 }
 
-// create a service for the deployment
+// mountContainerConfigs projects every ContainerConfigFile onto the matching
+// container in podSpec as a file volume, adding one volume per distinct
+// source (the synthesized ConfigMap, or each referenced Secret) and one
+// volume mount per file, with subPath derived from the file's full path
+// (via configMapKeyForFile) so that sibling files can share a directory and
+// files with the same name in different directories don't collide. Volumes
+// are appended in sorted-by-name order rather than map iteration order,
+// since map ranges are unspecified and would otherwise make
+// PodSpec.Volumes — and thus the rendered pod template — spuriously
+// non-deterministic across renders of an unchanged workload.
+func mountContainerConfigs(podSpec *corev1.PodSpec, containers []v1alpha1.ContainerConfig, configMap *corev1.ConfigMap) error {
+	volumes := map[string]corev1.Volume{}
+
+	for _, cc := range containers {
+		idx := containerIndex(podSpec, cc.Name)
+		if idx < 0 {
+			return fmt.Errorf("container %q referenced in spec.containers has no matching entry in podSpec.containers", cc.Name)
+		}
+
+		for _, f := range cc.Config {
+			subPath := configMapKeyForFile(cc.Name, f.Path)
+			var volumeName string
+			switch {
+			case f.FromSecret != nil:
+				volumeName = "secret-" + f.FromSecret.Name
+				v := volumes[volumeName]
+				if v.Secret == nil {
+					v.Name = volumeName
+					v.Secret = &corev1.SecretVolumeSource{SecretName: f.FromSecret.Name}
+				}
+				v.Secret.Items = append(v.Secret.Items, corev1.KeyToPath{Key: f.FromSecret.Key, Path: subPath})
+				volumes[volumeName] = v
+			default:
+				volumeName = "configmap-" + configMap.GetName()
+				v := volumes[volumeName]
+				if v.ConfigMap == nil {
+					v.Name = volumeName
+					v.ConfigMap = &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: configMap.GetName()},
+					}
+				}
+				v.ConfigMap.Items = append(v.ConfigMap.Items, corev1.KeyToPath{Key: configMapKeyForFile(cc.Name, f.Path), Path: subPath})
+				volumes[volumeName] = v
+			}
+
+			podSpec.Containers[idx].VolumeMounts = append(podSpec.Containers[idx].VolumeMounts, corev1.VolumeMount{
+				Name:      volumeName,
+				MountPath: f.Path,
+				SubPath:   subPath,
+			})
+		}
+	}
+
+	volumeNames := make([]string, 0, len(volumes))
+	for name := range volumes {
+		volumeNames = append(volumeNames, name)
+	}
+	sort.Strings(volumeNames)
+	for _, name := range volumeNames {
+		podSpec.Volumes = append(podSpec.Volumes, volumes[name])
+	}
+	return nil
+}
+
+// containerIndex returns the index of the container with the given name in
+// podSpec.Containers, or -1 if there is no such container.
+func containerIndex(podSpec *corev1.PodSpec, name string) int {
+	for i := range podSpec.Containers {
+		if podSpec.Containers[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// deleteOwnedService deletes the Service owned by workload, if any, so that
+// switching spec.service.disabled to true doesn't leave an orphaned Service
+// behind.
+func (r *ContainerizedReconciler) deleteOwnedService(ctx context.Context, workload *v1alpha1.Containerized) error {
+	var svc corev1.Service
+	key := client.ObjectKey{Namespace: workload.GetNamespace(), Name: workload.GetName()}
+	if err := r.apiReader.Get(ctx, key, &svc); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(&svc, workload) {
+		return nil
+	}
+	return client.IgnoreNotFound(r.Delete(ctx, &svc))
+}
+
+// renderService renders the Service exposing workload according to its
+// spec.service configuration. It returns a nil Service, with no error, if
+// the workload has opted out of exposure via spec.service.disabled.
 func (r *ContainerizedReconciler) renderService(ctx context.Context,
 	workload *v1alpha1.Containerized) (*corev1.Service, error) {
-	// create a service for the workload
+	svcSpec := workload.Spec.Service
+	if svcSpec != nil && svcSpec.Disabled {
+		return nil, nil
+	}
+
+	exposeType := v1alpha1.ServiceExposeTypeClusterIP
+	var explicitPorts []v1alpha1.ServicePort
+	var loadBalancerClass *string
+	var externalTrafficPolicy corev1.ServiceExternalTrafficPolicyType
+	var sessionAffinity corev1.ServiceAffinity
+	if svcSpec != nil {
+		if svcSpec.Type != "" {
+			exposeType = svcSpec.Type
+		}
+		explicitPorts = svcSpec.Ports
+		loadBalancerClass = svcSpec.LoadBalancerClass
+		externalTrafficPolicy = svcSpec.ExternalTrafficPolicy
+		sessionAffinity = svcSpec.SessionAffinity
+	}
+
 	service := &corev1.Service{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       serviceKind,
@@ -215,48 +461,128 @@ func (r *ContainerizedReconciler) renderService(ctx context.Context,
 			Selector: map[string]string{
 				labelNameKey: workload.GetName(),
 			},
-			Ports: []corev1.ServicePort{},
-			Type:  corev1.ServiceTypeClusterIP,
+			Ports:                 []corev1.ServicePort{},
+			ExternalTrafficPolicy: externalTrafficPolicy,
+			SessionAffinity:       sessionAffinity,
 		},
 	}
-	// create a port for each ports in the all the containers
-	var servicePort int32 = 8080
-	for _, container := range workload.Spec.PodSpec.Containers {
-		for _, port := range container.Ports {
-			sp := corev1.ServicePort{
-				Name:       port.Name,
-				Protocol:   port.Protocol,
-				Port:       servicePort,
-				TargetPort: intstr.FromInt(int(port.ContainerPort)),
-			}
-			service.Spec.Ports = append(service.Spec.Ports, sp)
-			servicePort++
-		}
+
+	switch exposeType {
+	case v1alpha1.ServiceExposeTypeNone:
+		service.Spec.Type = corev1.ServiceTypeClusterIP
+		service.Spec.ClusterIP = corev1.ClusterIPNone
+	case v1alpha1.ServiceExposeTypeNodePort:
+		service.Spec.Type = corev1.ServiceTypeNodePort
+	case v1alpha1.ServiceExposeTypeLoadBalancer:
+		service.Spec.Type = corev1.ServiceTypeLoadBalancer
+		service.Spec.LoadBalancerClass = loadBalancerClass
+	default:
+		service.Spec.Type = corev1.ServiceTypeClusterIP
 	}
 
-	// always set the controller reference so that we can watch this service and
+	ports, err := servicePorts(workload, explicitPorts)
+	if err != nil {
+		return nil, err
+	}
+	service.Spec.Ports = ports
+
 	if err := ctrl.SetControllerReference(workload, service, r.Scheme); err != nil {
 		return nil, err
 	}
 	return service, nil
 }
 
+// servicePorts resolves the ServicePorts to put on the Service. If explicit
+// is non-empty each entry is matched by name against the workload's
+// container ports; otherwise every container port is exposed as-is.
+func servicePorts(workload *v1alpha1.Containerized, explicit []v1alpha1.ServicePort) ([]corev1.ServicePort, error) {
+	if len(explicit) == 0 {
+		var ports []corev1.ServicePort
+		for _, container := range workload.Spec.PodSpec.Containers {
+			for _, port := range container.Ports {
+				ports = append(ports, corev1.ServicePort{
+					Name:       port.Name,
+					Protocol:   port.Protocol,
+					Port:       port.ContainerPort,
+					TargetPort: intstr.FromInt(int(port.ContainerPort)),
+				})
+			}
+		}
+		return ports, nil
+	}
+
+	containerPorts := map[string]corev1.ContainerPort{}
+	for _, container := range workload.Spec.PodSpec.Containers {
+		for _, port := range container.Ports {
+			containerPorts[port.Name] = port
+		}
+	}
+
+	ports := make([]corev1.ServicePort, 0, len(explicit))
+	for _, sp := range explicit {
+		cp, ok := containerPorts[sp.Name]
+		if !ok {
+			return nil, fmt.Errorf("spec.service.ports references %q, which is not a named port on any container", sp.Name)
+		}
+		port := sp.Port
+		if port == 0 {
+			port = cp.ContainerPort
+		}
+		targetPort := sp.TargetPort
+		if targetPort == 0 {
+			targetPort = cp.ContainerPort
+		}
+		ports = append(ports, corev1.ServicePort{
+			Name:       sp.Name,
+			Protocol:   cp.Protocol,
+			Port:       port,
+			TargetPort: intstr.FromInt(int(targetPort)),
+			NodePort:   sp.NodePort,
+		})
+	}
+	return ports, nil
+}
+
+// ownedTypes are the kinds SetupWithManager watches via Owns, together with
+// whether each is registered metadata-only. We only need the owner
+// reference and name/UID of owned Deployments and Services to re-enqueue
+// the workload and populate Status.Resources, so those are watched as
+// metadata-only to avoid caching every Deployment and Service in the
+// cluster; the up-to-date check in update.ReconcileConfigMap reads
+// ConfigMaps through the cached client and needs the full object, so
+// ConfigMaps are watched in full. Declared as data, rather than inlined
+// into SetupWithManager, so a unit test can assert this wiring without
+// standing up a real manager.
+var ownedTypes = []struct {
+	object       client.Object
+	metadataOnly bool
+}{
+	{&appsv1.Deployment{}, true},
+	{&corev1.Service{}, true},
+	{&corev1.ConfigMap{}, false},
+}
+
 func (r *ContainerizedReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.record = event.NewAPIRecorder(mgr.GetEventRecorderFor("Containerized")).
 		WithAnnotations("controller", "Containerized")
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.Containerized{}).
-		Owns(&appsv1.Deployment{}).
-		Owns(&corev1.Service{}).
-		Complete(r)
+	b := ctrl.NewControllerManagedBy(mgr).For(&v1alpha1.Containerized{})
+	for _, ot := range ownedTypes {
+		if ot.metadataOnly {
+			b = b.Owns(ot.object, builder.OnlyMetadata)
+		} else {
+			b = b.Owns(ot.object)
+		}
+	}
+	return b.Complete(r)
 }
 
 // Setup adds a controller that reconciles MetricsTrait.
 func Setup(mgr ctrl.Manager) error {
 	reconciler := ContainerizedReconciler{
-		Client: mgr.GetClient(),
-		log:    ctrl.Log.WithName("Containerized"),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		log:       ctrl.Log.WithName("Containerized"),
+		Scheme:    mgr.GetScheme(),
+		apiReader: mgr.GetAPIReader(),
 	}
 	return reconciler.SetupWithManager(mgr)
 }
@@ -0,0 +1,126 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerized
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cloud-native-application/rudrx/api/v1alpha1"
+)
+
+// TestConfigMapKeyForFile_DistinctPaths verifies that two files with the same
+// base name in different directories of the same container get distinct keys.
+func TestConfigMapKeyForFile_DistinctPaths(t *testing.T) {
+	a := configMapKeyForFile("web", "/etc/foo/app.conf")
+	b := configMapKeyForFile("web", "/etc/bar/app.conf")
+	if a == b {
+		t.Fatalf("expected distinct keys for /etc/foo/app.conf and /etc/bar/app.conf, got %q for both", a)
+	}
+}
+
+// TestMountContainerConfigs_NoSubPathCollision verifies that two
+// ContainerConfigFiles in the same container with the same base name but
+// different directories each get their own ConfigMap data key and a unique
+// subPath, instead of silently overwriting one another.
+func TestMountContainerConfigs_NoSubPathCollision(t *testing.T) {
+	podSpec := &corev1.PodSpec{
+		Containers: []corev1.Container{{Name: "web"}},
+	}
+	containers := []v1alpha1.ContainerConfig{
+		{
+			Name: "web",
+			Config: []v1alpha1.ContainerConfigFile{
+				{Path: "/etc/foo/app.conf", Value: "foo"},
+				{Path: "/etc/bar/app.conf", Value: "bar"},
+			},
+		},
+	}
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "web-config"}}
+
+	if err := mountContainerConfigs(podSpec, containers, configMap); err != nil {
+		t.Fatalf("mountContainerConfigs: %v", err)
+	}
+
+	mounts := podSpec.Containers[0].VolumeMounts
+	if len(mounts) != 2 {
+		t.Fatalf("expected 2 volume mounts, got %d", len(mounts))
+	}
+	if mounts[0].SubPath == mounts[1].SubPath {
+		t.Errorf("expected distinct subPaths, got %q for both", mounts[0].SubPath)
+	}
+
+	if len(podSpec.Volumes) != 1 {
+		t.Fatalf("expected 1 volume (both files share the same ConfigMap), got %d", len(podSpec.Volumes))
+	}
+	items := podSpec.Volumes[0].ConfigMap.Items
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items in the ConfigMap volume, got %d", len(items))
+	}
+	if items[0].Path == items[1].Path {
+		t.Errorf("expected distinct item paths, got %q for both", items[0].Path)
+	}
+	if items[0].Key == items[1].Key {
+		t.Errorf("expected distinct ConfigMap data keys, got %q for both", items[0].Key)
+	}
+}
+
+// TestMountContainerConfigs_DeterministicVolumeOrder verifies that a
+// workload mixing an inline (ConfigMap-sourced) file with fromSecret files
+// across two different Secrets renders PodSpec.Volumes in the same order on
+// every call, since the volumes are collected into a map keyed by volume
+// name before being appended. Without sorting, this would make the rendered
+// pod template spuriously differ from one reconcile to the next and defeat
+// deploymentUpToDate's equality check.
+func TestMountContainerConfigs_DeterministicVolumeOrder(t *testing.T) {
+	containers := []v1alpha1.ContainerConfig{
+		{
+			Name: "web",
+			Config: []v1alpha1.ContainerConfigFile{
+				{Path: "/etc/app.conf", Value: "inline"},
+				{Path: "/etc/z-secret.conf", FromSecret: &v1alpha1.SecretKeyRef{Name: "z-secret", Key: "conf"}},
+				{Path: "/etc/a-secret.conf", FromSecret: &v1alpha1.SecretKeyRef{Name: "a-secret", Key: "conf"}},
+			},
+		},
+	}
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "web-config"}}
+
+	var names [][]string
+	for i := 0; i < 10; i++ {
+		podSpec := &corev1.PodSpec{Containers: []corev1.Container{{Name: "web"}}}
+		if err := mountContainerConfigs(podSpec, containers, configMap); err != nil {
+			t.Fatalf("mountContainerConfigs: %v", err)
+		}
+		var got []string
+		for _, v := range podSpec.Volumes {
+			got = append(got, v.Name)
+		}
+		names = append(names, got)
+	}
+	for i := 1; i < len(names); i++ {
+		if len(names[i]) != len(names[0]) {
+			t.Fatalf("run %d: expected %d volumes, got %d", i, len(names[0]), len(names[i]))
+		}
+		for j := range names[0] {
+			if names[i][j] != names[0][j] {
+				t.Fatalf("volume order is not deterministic: run 0 = %v, run %d = %v", names[0], i, names[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerized
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/cloud-native-application/rudrx/api/v1alpha1"
+)
+
+// unhealthyRequeueAfter is how soon an unhealthy workload is re-reconciled so
+// that health status recovers without waiting for an external trigger.
+const unhealthyRequeueAfter = 10 * time.Second
+
+// computeHealth aggregates the readiness of the Deployment, and of the
+// Service if serviceEnabled, owned by workload into a single HealthStatus
+// and a human-readable message. It reports Healthy only when the Deployment
+// has observed the latest generation, every desired replica is ready, and
+// (when serviceEnabled) the Service has endpoints.
+func (r *ContainerizedReconciler) computeHealth(ctx context.Context,
+	workload *v1alpha1.Containerized, serviceEnabled bool) (v1alpha1.HealthStatus, string, error) {
+	var deploy appsv1.Deployment
+	key := client.ObjectKey{Namespace: workload.GetNamespace(), Name: workload.GetName()}
+	// Deployment is only watched as metadata (see SetupWithManager), so its
+	// status must be read directly from the API server rather than the cache.
+	if err := r.apiReader.Get(ctx, key, &deploy); err != nil {
+		if apierrors.IsNotFound(err) {
+			return v1alpha1.HealthStatusUnhealthy, "deployment not found yet", nil
+		}
+		return v1alpha1.HealthStatusUnknown, "", err
+	}
+
+	var desired int32 = 1
+	if deploy.Spec.Replicas != nil {
+		desired = *deploy.Spec.Replicas
+	}
+
+	generationMatches := deploy.Status.ObservedGeneration == deploy.Generation
+	replicasReady := deploy.Status.ReadyReplicas == desired
+
+	if !serviceEnabled {
+		message := fmt.Sprintf("%d/%d replicas ready, service exposure disabled", deploy.Status.ReadyReplicas, desired)
+		if generationMatches && replicasReady {
+			return v1alpha1.HealthStatusHealthy, message, nil
+		}
+		return v1alpha1.HealthStatusUnhealthy, message, nil
+	}
+
+	var endpoints corev1.Endpoints
+	endpointCount := 0
+	if err := r.apiReader.Get(ctx, key, &endpoints); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return v1alpha1.HealthStatusUnknown, "", err
+		}
+	} else {
+		for _, subset := range endpoints.Subsets {
+			endpointCount += len(subset.Addresses)
+		}
+	}
+
+	message := fmt.Sprintf("%d/%d replicas ready, service has %d endpoints", deploy.Status.ReadyReplicas, desired, endpointCount)
+	if generationMatches && replicasReady && endpointCount > 0 {
+		return v1alpha1.HealthStatusHealthy, message, nil
+	}
+	return v1alpha1.HealthStatusUnhealthy, message, nil
+}
@@ -0,0 +1,118 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerized
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/cloud-native-application/rudrx/api/v1alpha1"
+)
+
+func newHealthTestScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("add appsv1 to scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("add corev1 to scheme: %v", err)
+	}
+	return scheme
+}
+
+// TestComputeHealth_ReadsViaAPIReader verifies that computeHealth reads the
+// Deployment and Endpoints status fields it needs through apiReader, which is
+// the only client that still sees those fields now that Deployments and
+// Services are watched as metadata-only (see SetupWithManager). A Deployment
+// or Endpoints object seeded only in apiReader's client, with a Client left
+// empty, still yields a correct health verdict.
+func TestComputeHealth_ReadsViaAPIReader(t *testing.T) {
+	workload := &v1alpha1.Containerized{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+
+	var replicas int32 = 2
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 1},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, ReadyReplicas: 2},
+	}
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Subsets: []corev1.EndpointSubset{
+			{Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}}},
+		},
+	}
+
+	apiReader := fake.NewClientBuilder().WithScheme(newHealthTestScheme(t)).WithObjects(deploy, endpoints).Build()
+
+	r := &ContainerizedReconciler{apiReader: apiReader}
+
+	status, message, err := r.computeHealth(context.Background(), workload, true)
+	if err != nil {
+		t.Fatalf("computeHealth: %v", err)
+	}
+	if status != v1alpha1.HealthStatusHealthy {
+		t.Errorf("expected HealthStatusHealthy, got %v (%s)", status, message)
+	}
+}
+
+// TestComputeHealth_DeploymentNotFound verifies that an as-yet-uncreated
+// Deployment (not found via apiReader) is reported unhealthy rather than
+// erroring.
+func TestComputeHealth_DeploymentNotFound(t *testing.T) {
+	workload := &v1alpha1.Containerized{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	apiReader := fake.NewClientBuilder().WithScheme(newHealthTestScheme(t)).Build()
+	r := &ContainerizedReconciler{apiReader: apiReader}
+
+	status, _, err := r.computeHealth(context.Background(), workload, true)
+	if err != nil {
+		t.Fatalf("computeHealth: %v", err)
+	}
+	if status != v1alpha1.HealthStatusUnhealthy {
+		t.Errorf("expected HealthStatusUnhealthy, got %v", status)
+	}
+}
+
+// TestComputeHealth_ServiceDisabledSkipsEndpoints verifies that when Service
+// exposure is disabled, health is determined solely from the Deployment and
+// no Endpoints lookup is required to report Healthy.
+func TestComputeHealth_ServiceDisabledSkipsEndpoints(t *testing.T) {
+	workload := &v1alpha1.Containerized{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+
+	var replicas int32 = 1
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 1},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, ReadyReplicas: 1},
+	}
+	apiReader := fake.NewClientBuilder().WithScheme(newHealthTestScheme(t)).WithObjects(deploy).Build()
+	r := &ContainerizedReconciler{apiReader: apiReader}
+
+	status, _, err := r.computeHealth(context.Background(), workload, false)
+	if err != nil {
+		t.Fatalf("computeHealth: %v", err)
+	}
+	if status != v1alpha1.HealthStatusHealthy {
+		t.Errorf("expected HealthStatusHealthy, got %v", status)
+	}
+}
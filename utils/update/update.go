@@ -0,0 +1,211 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package update provides shared fetch-diff-retry helpers for reconciling a
+// rendered workload resource (Deployment, Service, ConfigMap) against the
+// cluster. Each helper fetches the existing object and copies a fixed
+// allowlist of fields the caller renders onto it — leaving fields defaulted
+// by the API server or set by another actor (an HPA, a webhook, kubectl)
+// untouched — before retrying the update on conflict. This is a targeted
+// field merge, not a three-way (base/desired/existing) merge: a controller
+// that reuses this package is only protected against clobbering fields it
+// never renders, not against conflicting concurrent edits to fields it does
+// render. A controller that owns a field conditionally (renders it in some
+// but not all reconciles) needs its own merge, not this package.
+package update
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultBackoff is the retry policy used when an update is rejected because
+// the object was concurrently modified.
+var DefaultBackoff = retry.DefaultBackoff
+
+// ReconcileDeployment creates desired if no Deployment with its name exists,
+// or merges the fields this package owns (labels, annotations, replicas,
+// selector, pod template) onto the existing Deployment and updates it,
+// retrying on conflict. reader is used for the read so that callers watching
+// Deployments as metadata-only can read via an uncached client.Reader
+// instead of forcing a full-object informer. On return, desired holds the
+// object as stored in the cluster.
+func ReconcileDeployment(ctx context.Context, reader client.Reader, writer client.Writer, desired *appsv1.Deployment) error {
+	key := client.ObjectKey{Namespace: desired.GetNamespace(), Name: desired.GetName()}
+	var existing appsv1.Deployment
+	switch err := reader.Get(ctx, key, &existing); {
+	case apierrors.IsNotFound(err):
+		return writer.Create(ctx, desired)
+	case err != nil:
+		return err
+	case deploymentUpToDate(&existing, desired):
+		*desired = existing
+		return nil
+	}
+
+	return retry.RetryOnConflict(DefaultBackoff, func() error {
+		if err := reader.Get(ctx, key, &existing); err != nil {
+			return err
+		}
+		mergeDeployment(&existing, desired)
+		if err := writer.Update(ctx, &existing); err != nil {
+			return err
+		}
+		*desired = existing
+		return nil
+	})
+}
+
+// mergeDeployment copies onto existing a fixed allowlist of fields —
+// Labels, Annotations, Spec.Replicas, Spec.Selector, and Spec.Template —
+// leaving every other field — including ones the API server or another
+// actor defaulted or set, such as Spec.Strategy, Spec.RevisionHistoryLimit,
+// or Spec.ProgressDeadlineSeconds — untouched. Spec.Template is replaced as
+// a whole, so fields defaulted inside the existing pod template (such as a
+// pod DNSPolicy or SchedulerName) are not kept. This is not a three-way merge: it does not
+// diff against a last-applied base, so it offers no protection against a
+// concurrent edit to a field in this allowlist.
+func mergeDeployment(existing, desired *appsv1.Deployment) {
+	existing.Labels = desired.Labels
+	existing.Annotations = desired.Annotations
+	existing.Spec.Replicas = desired.Spec.Replicas
+	existing.Spec.Selector = desired.Spec.Selector
+	existing.Spec.Template = desired.Spec.Template
+}
+
+func deploymentUpToDate(existing, desired *appsv1.Deployment) bool {
+	return equality.Semantic.DeepEqual(existing.Labels, desired.Labels) &&
+		equality.Semantic.DeepEqual(existing.Annotations, desired.Annotations) &&
+		equality.Semantic.DeepEqual(existing.Spec.Replicas, desired.Spec.Replicas) &&
+		equality.Semantic.DeepEqual(existing.Spec.Selector, desired.Spec.Selector) &&
+		equality.Semantic.DeepEqual(existing.Spec.Template, desired.Spec.Template)
+}
+
+// ReconcileService creates desired if no Service with its name exists, or
+// merges the fields this package owns (labels, annotations, selector, ports,
+// type, load balancer class, external traffic policy, session affinity) onto
+// the existing Service and updates it, retrying on conflict. The existing
+// ClusterIP is preserved unless desired sets one (e.g. "None" for a
+// headless Service), in which case desired's value is copied. reader is
+// used for the read so that callers watching Services as metadata-only can
+// read via an uncached client.Reader instead of forcing a full-object
+// informer. On return, desired holds the object as stored in the cluster.
+func ReconcileService(ctx context.Context, reader client.Reader, writer client.Writer, desired *corev1.Service) error {
+	key := client.ObjectKey{Namespace: desired.GetNamespace(), Name: desired.GetName()}
+	var existing corev1.Service
+	switch err := reader.Get(ctx, key, &existing); {
+	case apierrors.IsNotFound(err):
+		return writer.Create(ctx, desired)
+	case err != nil:
+		return err
+	case serviceUpToDate(&existing, desired):
+		*desired = existing
+		return nil
+	}
+
+	return retry.RetryOnConflict(DefaultBackoff, func() error {
+		if err := reader.Get(ctx, key, &existing); err != nil {
+			return err
+		}
+		mergeService(&existing, desired)
+		if err := writer.Update(ctx, &existing); err != nil {
+			return err
+		}
+		*desired = existing
+		return nil
+	})
+}
+
+// mergeService copies onto existing a fixed allowlist of fields — Labels,
+// Annotations, Spec.Selector, Spec.Ports, Spec.Type,
+// Spec.ExternalTrafficPolicy, Spec.SessionAffinity, and
+// Spec.LoadBalancerClass — plus Spec.ClusterIP when desired sets it, and
+// leaves every other field, including ones the API server or another actor
+// defaulted or set such as an allocated Spec.ClusterIP, Spec.IPFamilies, or
+// Spec.HealthCheckNodePort, untouched. As with
+// mergeDeployment, this is a targeted field merge, not a three-way merge.
+func mergeService(existing, desired *corev1.Service) {
+	existing.Labels = desired.Labels
+	existing.Annotations = desired.Annotations
+	existing.Spec.Selector = desired.Spec.Selector
+	existing.Spec.Ports = desired.Spec.Ports
+	existing.Spec.Type = desired.Spec.Type
+	existing.Spec.ExternalTrafficPolicy = desired.Spec.ExternalTrafficPolicy
+	existing.Spec.SessionAffinity = desired.Spec.SessionAffinity
+	existing.Spec.LoadBalancerClass = desired.Spec.LoadBalancerClass
+	if desired.Spec.ClusterIP != "" {
+		existing.Spec.ClusterIP = desired.Spec.ClusterIP
+	}
+}
+
+func serviceUpToDate(existing, desired *corev1.Service) bool {
+	return equality.Semantic.DeepEqual(existing.Labels, desired.Labels) &&
+		equality.Semantic.DeepEqual(existing.Annotations, desired.Annotations) &&
+		equality.Semantic.DeepEqual(existing.Spec.Selector, desired.Spec.Selector) &&
+		equality.Semantic.DeepEqual(existing.Spec.Ports, desired.Spec.Ports) &&
+		existing.Spec.Type == desired.Spec.Type &&
+		existing.Spec.ExternalTrafficPolicy == desired.Spec.ExternalTrafficPolicy &&
+		existing.Spec.SessionAffinity == desired.Spec.SessionAffinity &&
+		equality.Semantic.DeepEqual(existing.Spec.LoadBalancerClass, desired.Spec.LoadBalancerClass) &&
+		(desired.Spec.ClusterIP == "" || existing.Spec.ClusterIP == desired.Spec.ClusterIP)
+}
+
+// ReconcileConfigMap creates desired if no ConfigMap with its name exists, or
+// merges its labels, annotations, Data and BinaryData onto the existing
+// ConfigMap and updates it, retrying on conflict. ConfigMaps have no
+// server-defaulted spec fields, so c is used for both the read and the
+// write. On return, desired holds the object as stored in the cluster.
+func ReconcileConfigMap(ctx context.Context, c client.Client, desired *corev1.ConfigMap) error {
+	key := client.ObjectKey{Namespace: desired.GetNamespace(), Name: desired.GetName()}
+	var existing corev1.ConfigMap
+	switch err := c.Get(ctx, key, &existing); {
+	case apierrors.IsNotFound(err):
+		return c.Create(ctx, desired)
+	case err != nil:
+		return err
+	case configMapUpToDate(&existing, desired):
+		*desired = existing
+		return nil
+	}
+
+	return retry.RetryOnConflict(DefaultBackoff, func() error {
+		if err := c.Get(ctx, key, &existing); err != nil {
+			return err
+		}
+		existing.Labels = desired.Labels
+		existing.Annotations = desired.Annotations
+		existing.Data = desired.Data
+		existing.BinaryData = desired.BinaryData
+		if err := c.Update(ctx, &existing); err != nil {
+			return err
+		}
+		*desired = existing
+		return nil
+	})
+}
+
+func configMapUpToDate(existing, desired *corev1.ConfigMap) bool {
+	return equality.Semantic.DeepEqual(existing.Data, desired.Data) &&
+		equality.Semantic.DeepEqual(existing.BinaryData, desired.BinaryData) &&
+		equality.Semantic.DeepEqual(existing.Labels, desired.Labels) &&
+		equality.Semantic.DeepEqual(existing.Annotations, desired.Annotations)
+}
@@ -0,0 +1,181 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("add appsv1 to scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("add corev1 to scheme: %v", err)
+	}
+	return scheme
+}
+
+// TestReconcileDeployment_PreservesServerDefaultedFields verifies that
+// merging a rendered Deployment onto an existing one does not clobber fields
+// the API server defaulted (or another actor set) that this package never
+// renders, such as Spec.Strategy and Spec.ProgressDeadlineSeconds.
+func TestReconcileDeployment_PreservesServerDefaultedFields(t *testing.T) {
+	var progressDeadline int32 = 600
+	existing := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: replicas(1),
+			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RollingUpdateDeploymentStrategyType},
+			RevisionHistoryLimit:   int32Ptr(10),
+			ProgressDeadlineSeconds: &progressDeadline,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: "web:v1"}}},
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(existing).Build()
+
+	desired := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: replicas(3),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: "web:v2"}}},
+			},
+		},
+	}
+
+	if err := ReconcileDeployment(context.Background(), c, c, desired); err != nil {
+		t.Fatalf("ReconcileDeployment: %v", err)
+	}
+
+	var got appsv1.Deployment
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "web"}, &got); err != nil {
+		t.Fatalf("get after reconcile: %v", err)
+	}
+
+	if got.Spec.Strategy.Type != appsv1.RollingUpdateDeploymentStrategyType {
+		t.Errorf("Strategy was clobbered: got %v", got.Spec.Strategy.Type)
+	}
+	if got.Spec.RevisionHistoryLimit == nil || *got.Spec.RevisionHistoryLimit != 10 {
+		t.Errorf("RevisionHistoryLimit was clobbered: got %v", got.Spec.RevisionHistoryLimit)
+	}
+	if got.Spec.ProgressDeadlineSeconds == nil || *got.Spec.ProgressDeadlineSeconds != 600 {
+		t.Errorf("ProgressDeadlineSeconds was clobbered: got %v", got.Spec.ProgressDeadlineSeconds)
+	}
+	if *got.Spec.Replicas != 3 {
+		t.Errorf("Replicas was not merged: got %d", *got.Spec.Replicas)
+	}
+	if got.Spec.Template.Spec.Containers[0].Image != "web:v2" {
+		t.Errorf("Template was not merged: got image %q", got.Spec.Template.Spec.Containers[0].Image)
+	}
+}
+
+// TestReconcileDeployment_NoOpWhenUpToDate verifies that reconciling an
+// already up-to-date Deployment does not attempt an update, which would
+// otherwise show up as permanent reconcile churn.
+func TestReconcileDeployment_NoOpWhenUpToDate(t *testing.T) {
+	desired := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: replicas(2),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: "web:v1"}}},
+			},
+		},
+	}
+	existing := desired.DeepCopy()
+	// Simulate server-side defaulting that ReconcileDeployment does not own.
+	existing.Spec.Strategy = appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	existing.ResourceVersion = "1"
+
+	c := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(existing).Build()
+
+	if err := ReconcileDeployment(context.Background(), c, c, desired); err != nil {
+		t.Fatalf("ReconcileDeployment: %v", err)
+	}
+
+	var got appsv1.Deployment
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "web"}, &got); err != nil {
+		t.Fatalf("get after reconcile: %v", err)
+	}
+	if got.ResourceVersion != "1" {
+		t.Errorf("expected no update to be issued, but resourceVersion changed to %q", got.ResourceVersion)
+	}
+}
+
+// TestReconcileService_PreservesClusterIP verifies that reconciling a
+// ClusterIP Service does not clobber the immutable ClusterIP the API server
+// allocated on creation.
+func TestReconcileService_PreservesClusterIP(t *testing.T) {
+	existing := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Selector:  map[string]string{"app": "web"},
+			ClusterIP: "10.0.0.5",
+			Type:      corev1.ServiceTypeClusterIP,
+			Ports:     []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(existing).Build()
+
+	desired := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "web"},
+			Type:     corev1.ServiceTypeClusterIP,
+			Ports:    []corev1.ServicePort{{Name: "http", Port: 81, TargetPort: intstr.FromInt(8081)}},
+		},
+	}
+
+	if err := ReconcileService(context.Background(), c, c, desired); err != nil {
+		t.Fatalf("ReconcileService: %v", err)
+	}
+
+	var got corev1.Service
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "web"}, &got); err != nil {
+		t.Fatalf("get after reconcile: %v", err)
+	}
+	if got.Spec.ClusterIP != "10.0.0.5" {
+		t.Errorf("ClusterIP was clobbered: got %q", got.Spec.ClusterIP)
+	}
+	if got.Spec.Ports[0].Port != 81 {
+		t.Errorf("Ports were not merged: got %d", got.Spec.Ports[0].Port)
+	}
+}
+
+func replicas(n int32) *int32 { return &n }
+func int32Ptr(n int32) *int32 { return &n }